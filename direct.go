@@ -2,25 +2,59 @@ package direct
 
 import (
 	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 )
 
-// directContextKey is the context key type for storing parameters in
-// context.Context.
-type directContextKey string
-
 // Middleware is a function designed to run some code before and/or after
 // another Handler.
 type Middleware func(http.Handler) http.Handler
 
 // Router routes HTTP requests.
 type Router struct {
-	routes []*route
-	mw     []Middleware
+	// trees holds one route tree per lowercased HTTP method, plus a "*" tree
+	// for routes registered to match every method. Only populated on a
+	// router returned by NewRouter, Host or Scheme; a Group's routes live in
+	// root's trees.
+	trees map[string]*node
+	mw    []Middleware
 	// NotFound is the http.Handler to call when no routes match. By default
-	// uses http.NotFoundHandler().
+	// uses http.NotFoundHandler(). Only consulted on a router returned by
+	// NewRouter, Host or Scheme; a Group defers to root's NotFound.
 	NotFound http.Handler
+
+	// root is the router whose trees routes registered through this one are
+	// flattened into. nil on a router returned by NewRouter.
+	root *Router
+	// prefix is prepended to every pattern registered through this router.
+	prefix string
+	// names maps route names to the route registered under them. Only
+	// populated on a router returned by NewRouter, Host or Scheme; a Group
+	// defers to root's names.
+	names map[string]*Route
+
+	// hosts holds the sub-routers created by Host/Scheme, tried in
+	// registration order before this router's own trees. Only populated on a
+	// router returned by NewRouter; a Group defers to root's hosts.
+	hosts []*Router
+	// hostSegs is the dot-separated, non-nil template this router was scoped
+	// to by Host; nil means any host matches. Only set on a router returned
+	// by Host or Scheme.
+	hostSegs []string
+	// scheme is the request scheme ("http" or "https") this router was
+	// scoped to by Scheme; empty means any scheme matches.
+	scheme string
+	// isScope marks a router returned by Host or Scheme, so chaining the
+	// other of the two refines the same entry instead of registering a new
+	// one.
+	isScope bool
 }
 
 // NewRouter makes a new Router. Middleware is optional and will be executed by
@@ -32,8 +66,156 @@ func NewRouter(mw ...Middleware) *Router {
 	}
 }
 
+// base returns the router whose trees and NotFound handler are actually used
+// to serve requests: r itself for a router made with NewRouter, or the
+// root router for one made with Group/Route.
+func (r *Router) base() *Router {
+	if r.root != nil {
+		return r.root
+	}
+	return r
+}
+
+// Group returns a sub-router that prepends prefix to every pattern
+// registered through it and runs mw after the parent's own middleware.
+// Routes registered on the returned Router are flattened into the parent's
+// route trees, so ServeHTTP's performance is unaffected by how deeply groups
+// are nested:
+//
+//	api := r.Group("/api/v1", authMw)
+//	api.Group("/admin", adminMw).HandleFunc(http.MethodGet, "/users", listUsers)
+//	// registers GET /api/v1/admin/users with authMw then adminMw applied
+func (r *Router) Group(prefix string, mw ...Middleware) *Router {
+	return &Router{
+		root:   r.base(),
+		prefix: r.prefix + prefix,
+		mw:     append(append([]Middleware{}, r.mw...), mw...),
+	}
+}
+
+// Route is a convenience for Group that passes the sub-router to fn instead
+// of returning it, useful for registering a batch of routes inline:
+//
+//	r.Route("/admin", func(admin *Router) {
+//	    admin.HandleFunc(http.MethodGet, "/users", listUsers)
+//	})
+func (r *Router) Route(prefix string, fn func(r *Router)) {
+	fn(r.Group(prefix))
+}
+
+// Host returns a sub-router whose routes only match requests whose Host
+// header matches tpl, enabling multi-tenant apps to serve different route
+// tables per host from a single Router:
+//
+//	r.Host("api.example.com").HandleFunc(http.MethodGet, "/users", listUsers)
+//	r.Host(":tenant.example.com").HandleFunc(http.MethodGet, "/users", listTenantUsers)
+//
+// tpl's dot-separated segments may use :name params, retrieved the same way
+// as path params via Param. In ServeHTTP, host matching runs before path
+// matching: a request whose Host matches tpl is served exclusively by the
+// returned router, never falling back to routes registered without a Host.
+// Calling Scheme on the returned Router refines the same entry rather than
+// registering a separate one.
+func (r *Router) Host(tpl string) *Router {
+	if r.isScope {
+		r.hostSegs = pathSegmentsSep(tpl, '.')
+		return r
+	}
+	base := r.base()
+	hr := &Router{
+		mw:       r.mw,
+		prefix:   r.prefix,
+		NotFound: http.NotFoundHandler(),
+		hostSegs: pathSegmentsSep(tpl, '.'),
+		isScope:  true,
+	}
+	base.hosts = append(base.hosts, hr)
+	return hr
+}
+
+// Scheme returns a sub-router whose routes only match requests made over the
+// given scheme ("http" or "https"), determined from the X-Forwarded-Proto
+// header if present, else from whether the connection used TLS. Like Host,
+// it short-circuits: only the returned router's own routes are considered
+// once its scheme matches. Calling Host on the returned Router refines the
+// same entry rather than registering a separate one.
+func (r *Router) Scheme(scheme string) *Router {
+	if r.isScope {
+		r.scheme = scheme
+		return r
+	}
+	base := r.base()
+	hr := &Router{
+		mw:       r.mw,
+		prefix:   r.prefix,
+		NotFound: http.NotFoundHandler(),
+		scheme:   scheme,
+		isScope:  true,
+	}
+	base.hosts = append(base.hosts, hr)
+	return hr
+}
+
+// matches reports whether req satisfies r's host and scheme scope (a
+// router not returned by Host/Scheme always matches), returning any
+// params captured from :name host segments.
+func (r *Router) matches(req *http.Request) (params, bool) {
+	if r.scheme != "" && !strings.EqualFold(r.scheme, requestScheme(req)) {
+		return nil, false
+	}
+	if r.hostSegs == nil {
+		return nil, true
+	}
+	return matchHostSegs(r.hostSegs, req.Host)
+}
+
+// requestScheme reports the scheme a server-side request was made over,
+// since req.URL.Scheme is left empty by net/http for incoming requests.
+func requestScheme(req *http.Request) string {
+	if proto := req.Header.Get("X-Forwarded-Proto"); proto != "" {
+		return proto
+	}
+	if req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// matchHostSegs matches host (its port, if any, stripped) against the
+// dot-separated tplSegs, capturing :name segments as params.
+func matchHostSegs(tplSegs []string, host string) (params, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	hostSegs := strings.Split(host, ".")
+	if len(hostSegs) != len(tplSegs) {
+		return nil, false
+	}
+	var ps params
+	for i, seg := range tplSegs {
+		if strings.HasPrefix(seg, ":") {
+			ps = append(ps, param{name: strings.TrimPrefix(seg, ":"), value: hostSegs[i]})
+			continue
+		}
+		if seg != hostSegs[i] {
+			return nil, false
+		}
+	}
+	return ps, true
+}
+
+// pathSegmentsSep is pathSegments generalized to an arbitrary separator, used
+// to split host templates on '.' instead of '/'.
+func pathSegmentsSep(pattern string, sep byte) []string {
+	trimmed := strings.Trim(pattern, string(sep))
+	if trimmed == "" {
+		return nil
+	}
+	return strings.Split(trimmed, string(sep))
+}
+
 func pathSegments(pattern string) []string {
-	return strings.Split(strings.Trim(pattern, "/"), "/")
+	return pathSegmentsSep(pattern, '/')
 }
 
 // Handle adds a handler with the specified method, pattern and optional
@@ -42,7 +224,23 @@ func pathSegments(pattern string) []string {
 // accessible via the Param function. If pattern ends with trailing /, it acts
 // as a prefix. Middleware is optional and will be executed by requests in the
 // order they are provided.
-func (r *Router) Handle(method, pattern string, handler http.Handler, mw ...Middleware) {
+//
+// A :name segment can be constrained with a trailing regexp in parens, e.g.
+// /item/:id(\d+) or /user/:name([a-z]+); segments that don't satisfy the
+// regexp are rejected. :id:int and :slug:uuid are shortcuts for well-known
+// constraints that additionally store a typed value in context, retrievable
+// via ParamInt/ParamUUID without re-parsing. Differently constrained params
+// may share the same position (tried in registration order), which is how
+// otherwise-ambiguous routes like /item/:id(\d+) and /item/:slug([a-z]+) can
+// coexist.
+//
+// Routes are stored in a tree per method, so lookups cost O(path segments)
+// rather than O(routes). Handle panics if pattern conflicts with a route
+// already registered for the same method, e.g. a static segment registered
+// where a param segment already exists at the same position, an
+// unconstrained param alongside another param at the same position, or a
+// duplicate constraint at the same position.
+func (r *Router) Handle(method, pattern string, handler http.Handler, mw ...Middleware) *Route {
 
 	// First, adapt handler specific middleware around this handler.
 	handler = adapt(handler, mw...)
@@ -50,39 +248,215 @@ func (r *Router) Handle(method, pattern string, handler http.Handler, mw ...Midd
 	// Then, adapt the application's general middleware to the handler chain.
 	handler = adapt(handler, r.mw...)
 
-	route := newRoute(method, pattern, handler)
-	r.routes = append(r.routes, route)
+	base := r.base()
+	if base.trees == nil {
+		base.trees = map[string]*node{}
+	}
+	m := strings.ToLower(method)
+	root, ok := base.trees[m]
+	if !ok {
+		root = &node{}
+		base.trees[m] = root
+	}
+	fullPattern := r.prefix + pattern
+	prefixAllowed := strings.HasSuffix(pattern, "/")
+	root.insert(fullPattern, pathSegments(fullPattern), prefixAllowed, handler)
+	return &Route{router: base, pattern: fullPattern}
 }
 
 // HandleFunc is the http.HandlerFunc alternative to http.Handle.
-func (r *Router) HandleFunc(method, pattern string, fn http.HandlerFunc, mw ...Middleware) {
-	r.Handle(method, pattern, fn, mw...)
+func (r *Router) HandleFunc(method, pattern string, fn http.HandlerFunc, mw ...Middleware) *Route {
+	return r.Handle(method, pattern, fn, mw...)
+}
+
+// Route represents a single registered route, returned by Handle/HandleFunc
+// so it can be named for URL reversal via Router.URL.
+type Route struct {
+	router  *Router
+	pattern string
+}
+
+// Name records name as an alias for route, so Router.URL(name, ...) can
+// rebuild its URL later. Name panics if name is already registered for a
+// different pattern.
+func (rt *Route) Name(name string) *Route {
+	if rt.router.names == nil {
+		rt.router.names = map[string]*Route{}
+	}
+	if existing, ok := rt.router.names[name]; ok && existing.pattern != rt.pattern {
+		panic(fmt.Sprintf("direct: route name %q is already registered for pattern %q", name, existing.pattern))
+	}
+	rt.router.names[name] = rt
+	return rt
+}
+
+// URL rebuilds the URL registered under name, substituting each :param
+// segment with its value from pairs, an alternating list of param name and
+// value (name1, value1, name2, value2, ...; values are formatted with
+// fmt.Sprint). It returns an error if name isn't registered, a param is
+// missing a value, or a value doesn't satisfy the constraint its param was
+// registered with.
+func (r *Router) URL(name string, pairs ...interface{}) (*url.URL, error) {
+	base := r.base()
+	route, ok := base.names[name]
+	if !ok {
+		return nil, fmt.Errorf("direct: no route named %q", name)
+	}
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("direct: URL: odd number of param name/value arguments for route %q", name)
+	}
+	values := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("direct: URL: param name at position %d must be a string", i)
+		}
+		values[key] = fmt.Sprint(pairs[i+1])
+	}
+
+	segs := pathSegments(route.pattern)
+	built := make([]string, 0, len(segs))
+	for _, seg := range segs {
+		if !strings.HasPrefix(seg, ":") {
+			built = append(built, seg)
+			continue
+		}
+		pname, constraint, _, _ := parseParamSegment(seg)
+		value, ok := values[pname]
+		if !ok {
+			return nil, fmt.Errorf("direct: URL: missing value for param %q in route %q", pname, name)
+		}
+		if constraint != nil && !constraint.MatchString(value) {
+			return nil, fmt.Errorf("direct: URL: value %q for param %q doesn't satisfy its constraint", value, pname)
+		}
+		built = append(built, value)
+	}
+
+	return &url.URL{Path: "/" + strings.Join(built, "/")}, nil
 }
 
-// ServeHTTP routes the incoming http.Request based on method and path
-// extracting path parameters as it goes.
+// ServeHTTP routes the incoming http.Request based on method and path,
+// extracting path parameters as it goes. If the path matches a route but the
+// method doesn't, a 405 is returned with an Allow header listing the methods
+// registered for that path.
+//
+// Host/Scheme-scoped sub-routers are tried first, in registration order; the
+// first one whose scope matches the request serves it exclusively, even if
+// none of its own routes match the path. Requests matching no scope fall
+// back to this router's own, scope-less routes.
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	base := r.base()
+	for _, hr := range base.hosts {
+		if hostParams, ok := hr.matches(req); ok {
+			hr.dispatch(w, req, hostParams)
+			return
+		}
+	}
+	base.dispatch(w, req, nil)
+}
+
+// dispatch matches req against r's own trees, prepending hostParams (params
+// captured from a Host template, if any) to whatever path params are found.
+func (r *Router) dispatch(w http.ResponseWriter, req *http.Request, hostParams params) {
 	method := strings.ToLower(req.Method)
-	for _, route := range r.routes {
-		if route.method != method && route.method != "*" {
-			continue
+	segs := pathSegments(req.URL.Path)
+
+	if root, ok := r.trees[method]; ok {
+		if h, ps, ok := root.match(segs); ok {
+			r.serve(w, req, h, append(append(params{}, hostParams...), ps...))
+			return
 		}
-		if ctx, ok := route.match(req.Context(), r, req.URL.Path); ok {
-			route.handler.ServeHTTP(w, req.WithContext(ctx))
+	}
+	if root, ok := r.trees["*"]; ok {
+		if h, ps, ok := root.match(segs); ok {
+			r.serve(w, req, h, append(append(params{}, hostParams...), ps...))
 			return
 		}
 	}
+
+	if allowed := r.allowedMethods(segs, method); len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
 	r.NotFound.ServeHTTP(w, req)
 }
 
+func (r *Router) serve(w http.ResponseWriter, req *http.Request, h http.Handler, ps params) {
+	ctx := context.WithValue(req.Context(), paramsContextKey{}, ps)
+	h.ServeHTTP(w, req.WithContext(ctx))
+}
+
+// allowedMethods returns the uppercased, sorted list of methods (other than
+// exclude) that have a route matching segs.
+func (r *Router) allowedMethods(segs []string, exclude string) []string {
+	var methods []string
+	for m, root := range r.trees {
+		if m == exclude || m == "*" {
+			continue
+		}
+		if _, _, ok := root.match(segs); ok {
+			methods = append(methods, strings.ToUpper(m))
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
 // Param gets the path parameter from the specified Context. Returns an empty
 // string if the parameter was not found.
 func Param(ctx context.Context, param string) string {
-	vStr, ok := ctx.Value(directContextKey(param)).(string)
+	ps, ok := ctx.Value(paramsContextKey{}).(params)
 	if !ok {
 		return ""
 	}
-	return vStr
+	v, _ := ps.get(param)
+	return v
+}
+
+// ParamInt gets the path parameter matched via a ":name:int" constraint,
+// already parsed as an int. Returns 0 if the parameter wasn't found or wasn't
+// declared with the :int constraint.
+func ParamInt(ctx context.Context, param string) int {
+	ps, ok := ctx.Value(paramsContextKey{}).(params)
+	if !ok {
+		return 0
+	}
+	v, _ := ps.getTyped(param)
+	i, _ := v.(int)
+	return i
+}
+
+// UUID is a parsed RFC 4122 UUID, as matched by a ":name:uuid" path parameter
+// constraint.
+type UUID [16]byte
+
+// ParamUUID gets the path parameter matched via a ":name:uuid" constraint,
+// already parsed as a UUID. Returns the zero UUID if the parameter wasn't
+// found or wasn't declared with the :uuid constraint.
+func ParamUUID(ctx context.Context, param string) UUID {
+	ps, ok := ctx.Value(paramsContextKey{}).(params)
+	if !ok {
+		return UUID{}
+	}
+	v, _ := ps.getTyped(param)
+	u, _ := v.(UUID)
+	return u
+}
+
+func parseUUID(s string) (UUID, error) {
+	var u UUID
+	parts := strings.Split(s, "-")
+	if len(parts) != 5 {
+		return u, fmt.Errorf("direct: invalid uuid %q", s)
+	}
+	b, err := hex.DecodeString(strings.Join(parts, ""))
+	if err != nil || len(b) != 16 {
+		return u, fmt.Errorf("direct: invalid uuid %q", s)
+	}
+	copy(u[:], b)
+	return u, nil
 }
 
 // adapt creates a new Handler by wrapping middleware around a final handler.
@@ -94,49 +468,217 @@ func adapt(h http.Handler, mw ...Middleware) http.Handler {
 	return h
 }
 
-type route struct {
-	method  string
-	segs    []string
-	handler http.Handler
-	prefix  bool
+// param is a single matched path parameter. typed holds the value already
+// parsed into its constraint's Go type (int, UUID, ...), or nil if the
+// param's constraint doesn't produce one.
+type param struct {
+	name  string
+	value string
+	typed interface{}
 }
 
-func newRoute(method, pattern string, handler http.Handler) *route {
-	return &route{
-		method:  strings.ToLower(method),
-		segs:    pathSegments(pattern),
-		handler: handler,
-		prefix:  strings.HasSuffix(pattern, "/") || strings.HasSuffix(pattern, "..."),
+// params is the lightweight stand-in for a map of matched path parameters.
+// It is stored once per request in the request context rather than one
+// context.Value per parameter.
+type params []param
+
+func (p params) get(name string) (string, bool) {
+	for _, kv := range p {
+		if kv.name == name {
+			return kv.value, true
+		}
 	}
+	return "", false
 }
 
-func (r *route) match(ctx context.Context, router *Router, path string) (context.Context, bool) {
-	segs := pathSegments(path)
-	if len(segs) > len(r.segs) && !r.prefix {
-		return nil, false
+func (p params) getTyped(name string) (interface{}, bool) {
+	for _, kv := range p {
+		if kv.name == name {
+			return kv.typed, kv.typed != nil
+		}
 	}
-	for i, seg := range r.segs {
-		if i > len(segs)-1 {
-			return nil, false
+	return nil, false
+}
+
+// paramKind classifies the well-known shortcut constraints (:int, :uuid)
+// that additionally store a typed value in context.
+type paramKind int
+
+const (
+	paramKindString paramKind = iota
+	paramKindInt
+	paramKindUUID
+)
+
+var (
+	intConstraint  = regexp.MustCompile(`^-?[0-9]+$`)
+	uuidConstraint = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// parseParamSegment splits a ":name", ":name(regexp)" or ":name:type"
+// pattern segment into its name and constraint. key identifies the
+// constraint for conflict detection ("" means unconstrained); it's the raw
+// regexp source for an explicit constraint, or the shortcut's name ("int",
+// "uuid") for a well-known one.
+func parseParamSegment(seg string) (name string, constraint *regexp.Regexp, key string, kind paramKind) {
+	body := strings.TrimPrefix(seg, ":")
+	if i := strings.IndexByte(body, '('); i != -1 && strings.HasSuffix(body, ")") {
+		pattern := body[i+1 : len(body)-1]
+		return body[:i], regexp.MustCompile(`^(?:` + pattern + `)$`), pattern, paramKindString
+	}
+	if i := strings.LastIndexByte(body, ':'); i != -1 {
+		switch body[i+1:] {
+		case "int":
+			return body[:i], intConstraint, "int", paramKindInt
+		case "uuid":
+			return body[:i], uuidConstraint, "uuid", paramKindUUID
 		}
-		isParam := false
-		if strings.HasPrefix(seg, ":") {
-			isParam = true
-			seg = strings.TrimPrefix(seg, ":")
+	}
+	return body, nil, "", paramKindString
+}
+
+// paramsContextKey is the context key type used to store params in
+// context.Context.
+type paramsContextKey struct{}
+
+// node is one segment position in a per-method route tree. A node holds a
+// static child per literal next segment, a catch-all child for a "prefix..."
+// segment, and a list of param children. A position can't mix static and
+// param children, since there'd be no way to disambiguate them; it can hold
+// several param children as long as each carries a distinct constraint to
+// disambiguate by, tried in registration order.
+type node struct {
+	children           map[string]*node
+	paramChildren      []*node
+	paramName          string
+	paramConstraint    *regexp.Regexp
+	paramConstraintKey string
+	paramKind          paramKind
+	catchAll           *node
+	catchAllPrefix     string
+	handler            http.Handler
+	// prefixAllowed is true if the route registered at this node ended in a
+	// trailing slash, meaning extra, unmatched trailing segments are allowed.
+	prefixAllowed bool
+}
+
+// insert adds handler to the tree rooted at n for pattern's segments,
+// panicking if it conflicts with a route already registered at the same
+// position.
+func (n *node) insert(pattern string, segs []string, prefixAllowed bool, handler http.Handler) {
+	cur := n
+	for i, seg := range segs {
+		last := i == len(segs)-1
+		if last && strings.HasSuffix(seg, "...") {
+			litPrefix := seg[:len(seg)-3]
+			if cur.catchAll == nil {
+				cur.catchAll = &node{catchAllPrefix: litPrefix}
+			} else if cur.catchAll.catchAllPrefix != litPrefix {
+				panic(fmt.Sprintf("direct: route conflict: pattern %q conflicts with an existing catch-all registered at the same position", pattern))
+			}
+			if cur.catchAll.handler != nil {
+				panic(fmt.Sprintf("direct: route conflict: pattern %q is already registered", pattern))
+			}
+			cur.catchAll.handler = handler
+			return
 		}
-		if !isParam { // verbatim check
-			if strings.HasSuffix(seg, "...") {
-				if strings.HasPrefix(segs[i], seg[:len(seg)-3]) {
-					return ctx, true
+		if strings.HasPrefix(seg, ":") {
+			if len(cur.children) > 0 {
+				panic(fmt.Sprintf("direct: route conflict: pattern %q has a param where a static segment is already registered", pattern))
+			}
+			name, constraint, key, kind := parseParamSegment(seg)
+			for _, existing := range cur.paramChildren {
+				if existing.paramConstraintKey == key {
+					panic(fmt.Sprintf("direct: route conflict: pattern %q duplicates a constraint already registered at the same position", pattern))
+				}
+				if key == "" || existing.paramConstraintKey == "" {
+					panic(fmt.Sprintf("direct: route conflict: pattern %q has an unconstrained param alongside another param at the same position", pattern))
 				}
 			}
-			if seg != segs[i] {
-				return nil, false
+			child := &node{paramName: name, paramConstraint: constraint, paramConstraintKey: key, paramKind: kind}
+			cur.paramChildren = append(cur.paramChildren, child)
+			cur = child
+		} else {
+			if len(cur.paramChildren) > 0 {
+				panic(fmt.Sprintf("direct: route conflict: pattern %q has a static segment where a param is already registered at the same position", pattern))
+			}
+			if cur.children == nil {
+				cur.children = map[string]*node{}
+			}
+			child, ok := cur.children[seg]
+			if !ok {
+				child = &node{}
+				cur.children[seg] = child
+			}
+			cur = child
+		}
+		if last {
+			if cur.handler != nil {
+				panic(fmt.Sprintf("direct: route conflict: pattern %q is already registered", pattern))
+			}
+			cur.handler = handler
+			cur.prefixAllowed = prefixAllowed
+		}
+	}
+	if len(segs) == 0 {
+		if n.handler != nil {
+			panic(fmt.Sprintf("direct: route conflict: pattern %q is already registered", pattern))
+		}
+		n.handler = handler
+		n.prefixAllowed = prefixAllowed
+	}
+}
+
+// match walks the tree rooted at n for segs, returning the matched handler
+// and the path parameters gathered along the way.
+func (n *node) match(segs []string) (http.Handler, params, bool) {
+	var ps params
+	matched, ok := n.find(segs, 0, &ps)
+	if !ok {
+		return nil, nil, false
+	}
+	return matched.handler, ps, true
+}
+
+func (n *node) find(segs []string, idx int, ps *params) (*node, bool) {
+	if idx == len(segs) {
+		if n.handler != nil {
+			return n, true
+		}
+		return nil, false
+	}
+	seg := segs[idx]
+	if child, ok := n.children[seg]; ok {
+		if match, ok := child.find(segs, idx+1, ps); ok {
+			return match, true
+		}
+	}
+	for _, pc := range n.paramChildren {
+		if pc.paramConstraint != nil && !pc.paramConstraint.MatchString(seg) {
+			continue
+		}
+		p := param{name: pc.paramName, value: seg}
+		switch pc.paramKind {
+		case paramKindInt:
+			if v, err := strconv.Atoi(seg); err == nil {
+				p.typed = v
+			}
+		case paramKindUUID:
+			if v, err := parseUUID(seg); err == nil {
+				p.typed = v
 			}
 		}
-		if isParam {
-			ctx = context.WithValue(ctx, directContextKey(seg), segs[i])
+		*ps = append(*ps, p)
+		if match, ok := pc.find(segs, idx+1, ps); ok {
+			return match, true
 		}
+		*ps = (*ps)[:len(*ps)-1]
+	}
+	if n.catchAll != nil && strings.HasPrefix(seg, n.catchAll.catchAllPrefix) {
+		return n.catchAll, true
+	}
+	if n.handler != nil && n.prefixAllowed {
+		return n, true
 	}
-	return ctx, true
+	return nil, false
 }
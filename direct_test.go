@@ -239,6 +239,188 @@ func TestMultipleRoutesDifferentMethods(t *testing.T) {
 	assert(t, match == "PUT /route", fmt.Sprintf("unexpected: %s", match))
 }
 
+func TestRouteConflictPanics(t *testing.T) {
+	var tests = []struct {
+		name   string
+		routes [][2]string // pairs of {method, pattern}
+	}{
+		{
+			name:   "param vs param with different names",
+			routes: [][2]string{{"GET", "/users/:id"}, {"GET", "/users/:name"}},
+		},
+		{
+			name:   "static vs param",
+			routes: [][2]string{{"GET", "/users/:id"}, {"GET", "/users/admin"}},
+		},
+		{
+			name:   "param vs static",
+			routes: [][2]string{{"GET", "/users/admin"}, {"GET", "/users/:id"}},
+		},
+		{
+			name:   "duplicate route",
+			routes: [][2]string{{"GET", "/users/:id"}, {"GET", "/users/:id"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected a panic registering %v", test.routes)
+				}
+			}()
+			r := NewRouter()
+			for _, route := range test.routes {
+				r.Handle(route[0], route[1], http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			}
+		})
+	}
+}
+
+func TestGroupPrefixAndMiddleware(t *testing.T) {
+	logger := &testLogger{}
+	r := NewRouter(notify(logger, "root"))
+
+	api := r.Group("/api/v1", notify(logger, "api"))
+	admin := api.Group("/admin", notify(logger, "admin"))
+	admin.HandleFunc(http.MethodGet, "/users", func(w http.ResponseWriter, r *http.Request) {})
+
+	req, err := http.NewRequest(http.MethodGet, "/api/v1/admin/users", nil)
+	ok(t, err)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	equals(t, []string{
+		"root: before", "api: before", "admin: before",
+		"admin: after", "api: after", "root: after",
+	}, logger.history)
+
+	req, err = http.NewRequest(http.MethodGet, "/api/v1/admin/users", nil)
+	ok(t, err)
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, req)
+	equals(t, http.StatusOK, rec.Code)
+}
+
+func TestRoute(t *testing.T) {
+	r := NewRouter()
+	var matched bool
+	r.Route("/admin", func(admin *Router) {
+		admin.HandleFunc(http.MethodGet, "/ping", func(w http.ResponseWriter, r *http.Request) {
+			matched = true
+		})
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "/admin/ping", nil)
+	ok(t, err)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	assert(t, matched, "expected /admin/ping to match")
+}
+
+func TestURLRebuildsFromNamedRoute(t *testing.T) {
+	r := NewRouter()
+	r.HandleFunc(http.MethodGet, "/users/:id(\\d+)/posts/:slug", func(w http.ResponseWriter, r *http.Request) {}).Name("user.post")
+
+	u, err := r.URL("user.post", "id", 42, "slug", "hello-world")
+	ok(t, err)
+	equals(t, "/users/42/posts/hello-world", u.Path)
+}
+
+func TestURLErrorsOnUnknownNameMissingParamAndBadConstraint(t *testing.T) {
+	r := NewRouter()
+	r.HandleFunc(http.MethodGet, "/users/:id(\\d+)", func(w http.ResponseWriter, r *http.Request) {}).Name("user.show")
+
+	if _, err := r.URL("nope"); err == nil {
+		t.Fatal("expected an error for an unregistered route name")
+	}
+	if _, err := r.URL("user.show"); err == nil {
+		t.Fatal("expected an error for a missing param value")
+	}
+	if _, err := r.URL("user.show", "id", "not-a-number"); err == nil {
+		t.Fatal("expected an error for a value that doesn't satisfy the constraint")
+	}
+}
+
+func TestRouteNameConflictPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic naming two different patterns the same")
+		}
+	}()
+	r := NewRouter()
+	r.HandleFunc(http.MethodGet, "/users/:id", func(w http.ResponseWriter, r *http.Request) {}).Name("dup")
+	r.HandleFunc(http.MethodGet, "/accounts/:id", func(w http.ResponseWriter, r *http.Request) {}).Name("dup")
+}
+
+func TestParamConstraints(t *testing.T) {
+	r := NewRouter()
+	r.HandleFunc(http.MethodGet, "/item/:id(\\d+)", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("numeric:" + Param(r.Context(), "id")))
+	})
+	r.HandleFunc(http.MethodGet, "/item/:slug([a-z]+)", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("slug:" + Param(r.Context(), "slug")))
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "/item/123", nil)
+	ok(t, err)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	equals(t, "numeric:123", rec.Body.String())
+
+	req, err = http.NewRequest(http.MethodGet, "/item/abc", nil)
+	ok(t, err)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	equals(t, "slug:abc", rec.Body.String())
+
+	req, err = http.NewRequest(http.MethodGet, "/item/abc123", nil)
+	ok(t, err)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	equals(t, http.StatusNotFound, rec.Code)
+}
+
+func TestTypedParamShortcuts(t *testing.T) {
+	r := NewRouter()
+	var gotID int
+	var gotUUID UUID
+	r.HandleFunc(http.MethodGet, "/users/:id:int", func(w http.ResponseWriter, r *http.Request) {
+		gotID = ParamInt(r.Context(), "id")
+	})
+	r.HandleFunc(http.MethodGet, "/tokens/:token:uuid", func(w http.ResponseWriter, r *http.Request) {
+		gotUUID = ParamUUID(r.Context(), "token")
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "/users/42", nil)
+	ok(t, err)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	equals(t, 42, gotID)
+
+	req, err = http.NewRequest(http.MethodGet, "/tokens/not-a-uuid", nil)
+	ok(t, err)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	equals(t, http.StatusNotFound, rec.Code)
+
+	req, err = http.NewRequest(http.MethodGet, "/tokens/f47ac10b-58cc-4372-a567-0e02b2c3d479", nil)
+	ok(t, err)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	want, err := parseUUID("f47ac10b-58cc-4372-a567-0e02b2c3d479")
+	ok(t, err)
+	equals(t, want, gotUUID)
+}
+
+func TestMethodNotAllowedSetsAllowHeader(t *testing.T) {
+	r := NewRouter()
+	r.HandleFunc(http.MethodGet, "/route", func(w http.ResponseWriter, r *http.Request) {})
+	r.HandleFunc(http.MethodPost, "/route", func(w http.ResponseWriter, r *http.Request) {})
+
+	req, err := http.NewRequest(http.MethodDelete, "/route", nil)
+	ok(t, err)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	equals(t, http.StatusMethodNotAllowed, rec.Code)
+	equals(t, "GET, POST", rec.Header().Get("Allow"))
+}
+
 type testLogger struct{ history []string }
 
 func (l *testLogger) log(s string) { l.history = append(l.history, s) }
@@ -307,3 +489,63 @@ func TestMiddlewareExecutedInOrderAsProvided(t *testing.T) {
 		})
 	}
 }
+
+func TestHostRoutingWithSubdomainParam(t *testing.T) {
+	r := NewRouter()
+	r.Host(":tenant.example.com").HandleFunc(http.MethodGet, "/users", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, Param(r.Context(), "tenant"))
+	})
+	r.HandleFunc(http.MethodGet, "/users", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "default")
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "/users", nil)
+	ok(t, err)
+	req.Host = "acme.example.com"
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	equals(t, http.StatusOK, rec.Code)
+	equals(t, "acme", rec.Body.String())
+
+	req, err = http.NewRequest(http.MethodGet, "/users", nil)
+	ok(t, err)
+	req.Host = "other.example.org"
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	equals(t, http.StatusOK, rec.Code)
+	equals(t, "default", rec.Body.String())
+}
+
+func TestHostRoutingShortCircuitsOtherHosts(t *testing.T) {
+	r := NewRouter()
+	r.Host("admin.example.com").HandleFunc(http.MethodGet, "/dashboard", func(w http.ResponseWriter, r *http.Request) {})
+	r.HandleFunc(http.MethodGet, "/public", func(w http.ResponseWriter, r *http.Request) {})
+
+	req, err := http.NewRequest(http.MethodGet, "/public", nil)
+	ok(t, err)
+	req.Host = "admin.example.com"
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	equals(t, http.StatusNotFound, rec.Code)
+}
+
+func TestSchemeRoutingMatchesForwardedProto(t *testing.T) {
+	r := NewRouter()
+	r.Scheme("https").HandleFunc(http.MethodGet, "/secure", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "secure")
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "/secure", nil)
+	ok(t, err)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	equals(t, http.StatusOK, rec.Code)
+	equals(t, "secure", rec.Body.String())
+
+	req, err = http.NewRequest(http.MethodGet, "/secure", nil)
+	ok(t, err)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	equals(t, http.StatusNotFound, rec.Code)
+}
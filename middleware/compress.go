@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/johanronkko/direct"
+)
+
+// compressWriter streams writes through a compress/gzip or compress/flate
+// writer instead of straight to the underlying http.ResponseWriter.
+type compressWriter struct {
+	http.ResponseWriter
+	io.Writer
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	return w.Writer.Write(b)
+}
+
+// Compress returns a direct.Middleware that compresses the response body at
+// the given compress/flate compression level: with gzip if the client's
+// Accept-Encoding header mentions gzip, otherwise with deflate if it mentions
+// deflate. Quality values (q=) in the header are not considered. Requests
+// that don't advertise support for either are passed through uncompressed.
+func Compress(level int) direct.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accept := r.Header.Get("Accept-Encoding")
+
+			var (
+				cw       io.WriteCloser
+				err      error
+				encoding string
+			)
+			switch {
+			case strings.Contains(accept, "gzip"):
+				cw, err = gzip.NewWriterLevel(w, level)
+				encoding = "gzip"
+			case strings.Contains(accept, "deflate"):
+				cw, err = flate.NewWriter(w, level)
+				encoding = "deflate"
+			default:
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			defer cw.Close()
+
+			w.Header().Set("Content-Encoding", encoding)
+			w.Header().Add("Vary", "Accept-Encoding")
+			w.Header().Del("Content-Length")
+			next.ServeHTTP(&compressWriter{ResponseWriter: w, Writer: cw}, r)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCompressGzipsWhenAccepted(t *testing.T) {
+	h := Compress(gzip.DefaultCompression)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello, world"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("expected Content-Encoding gzip, got %q", got)
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("response body was not valid gzip: %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("failed to read decompressed body: %v", err)
+	}
+	if string(body) != "hello, world" {
+		t.Fatalf("expected decompressed body %q, got %q", "hello, world", body)
+	}
+}
+
+func TestCompressDeflatesWhenAccepted(t *testing.T) {
+	h := Compress(flate.DefaultCompression)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello, world"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "deflate")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "deflate" {
+		t.Fatalf("expected Content-Encoding deflate, got %q", got)
+	}
+	fr := flate.NewReader(rec.Body)
+	body, err := io.ReadAll(fr)
+	if err != nil {
+		t.Fatalf("failed to read decompressed body: %v", err)
+	}
+	if string(body) != "hello, world" {
+		t.Fatalf("expected decompressed body %q, got %q", "hello, world", body)
+	}
+}
+
+func TestCompressPassesThroughWhenNotAccepted(t *testing.T) {
+	h := Compress(gzip.DefaultCompression)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello, world"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Fatalf("expected no Content-Encoding header, got %q", got)
+	}
+	if rec.Body.String() != "hello, world" {
+		t.Fatalf("expected uncompressed body, got %q", rec.Body.String())
+	}
+}
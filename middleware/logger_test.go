@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoggerRecordsMethodPathAndStatus(t *testing.T) {
+	orig := log.Writer()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(orig)
+
+	h := Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hi"))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/widgets", nil)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	out := buf.String()
+	for _, want := range []string{"POST", "/widgets", "201", "2B"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected log line %q to contain %q", out, want)
+		}
+	}
+}
+
+func TestLoggerDefaultsToOKWhenNoStatusWritten(t *testing.T) {
+	orig := log.Writer()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(orig)
+
+	h := Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !strings.Contains(buf.String(), "200") {
+		t.Fatalf("expected log line %q to contain %q", buf.String(), "200")
+	}
+}
@@ -0,0 +1,25 @@
+// Package middleware provides direct.Middleware implementations for common
+// cross-cutting concerns: panic recovery, request logging, request IDs,
+// timeouts, CORS and response compression.
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recoverer is a direct.Middleware that recovers from panics anywhere in the
+// handler chain, logs the panic value and a stack trace, and responds with
+// 500 Internal Server Error instead of letting the panic crash the server.
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				log.Printf("panic: %v\n%s", rec, debug.Stack())
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
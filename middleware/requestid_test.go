@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestIDGeneratesWhenAbsent(t *testing.T) {
+	var gotInCtx string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotInCtx = GetRequestID(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	header := rec.Header().Get("X-Request-ID")
+	if header == "" {
+		t.Fatal("expected X-Request-ID response header to be set")
+	}
+	if gotInCtx != header {
+		t.Fatalf("expected context request ID %q to match response header %q", gotInCtx, header)
+	}
+}
+
+func TestRequestIDPropagatesExisting(t *testing.T) {
+	var gotInCtx string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotInCtx = GetRequestID(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "client-supplied-id")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Header().Get("X-Request-ID") != "client-supplied-id" {
+		t.Fatalf("expected existing request ID to be echoed back, got %q", rec.Header().Get("X-Request-ID"))
+	}
+	if gotInCtx != "client-supplied-id" {
+		t.Fatalf("expected existing request ID in context, got %q", gotInCtx)
+	}
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/johanronkko/direct"
+)
+
+// Timeout returns a direct.Middleware that gives the request context a
+// deadline of d and responds with 503 Service Unavailable if the handler
+// hasn't returned by the time it expires. The handler's writes are buffered
+// until it returns, so a handler that overruns d gets the 503 even if it had
+// already written a response.
+func Timeout(d time.Duration) direct.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.TimeoutHandler(next, d, "")
+	}
+}